@@ -0,0 +1,68 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/cli/cli/v2/internal/ghrepo"
+	"github.com/cli/cli/v2/internal/run"
+)
+
+// CurrentBranch returns the name of the currently checked-out branch, or ""
+// if HEAD is detached.
+func CurrentBranch(ctx context.Context) (string, error) {
+	out, err := run.PrepareCmd(exec.CommandContext(ctx, "git", "branch", "--show-current")).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ResolveBranchDefault returns the `branch.<branch>.gh-resolved` override for
+// branch, or "" if none is configured. This is consulted ahead of any
+// `remote.<name>.gh-resolved` value, so a per-branch default set via
+// `gh repo default --branch` shadows the directory-wide default.
+func ResolveBranchDefault(ctx context.Context, branch string) (string, error) {
+	if branch == "" {
+		return "", nil
+	}
+	out, err := run.PrepareCmd(exec.CommandContext(ctx, "git", "config", "--get", fmt.Sprintf("branch.%s.gh-resolved", branch))).Output()
+	if err != nil {
+		// no branch-level override configured
+		return "", nil
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ClearBranchDefault removes the `branch.<branch>.gh-resolved` override.
+func ClearBranchDefault(ctx context.Context, branch string) error {
+	return run.PrepareCmd(exec.CommandContext(ctx, "git", "config", "--unset", fmt.Sprintf("branch.%s.gh-resolved", branch))).Run()
+}
+
+// EffectiveDefault resolves remotes against the current branch's override,
+// shadowing any remote-level gh-resolved value when one is set.
+func EffectiveDefault(ctx context.Context, remotes Remotes) (Remotes, string, string, error) {
+	branch, err := CurrentBranch(ctx)
+	if err != nil || branch == "" {
+		return remotes, "", "", nil
+	}
+
+	override, err := ResolveBranchDefault(ctx, branch)
+	if err != nil || override == "" {
+		return remotes, branch, "", nil
+	}
+
+	effective := make(Remotes, len(remotes))
+	for i, r := range remotes {
+		gitRemoteCopy := *r.Remote
+		gitRemoteCopy.Resolved = ""
+		if ghrepo.FullName(r.Repo) == override {
+			gitRemoteCopy.Resolved = "base"
+		}
+		effective[i] = &Remote{Remote: &gitRemoteCopy, Repo: r.Repo}
+	}
+
+	return effective, branch, override, nil
+}
@@ -0,0 +1,44 @@
+package context
+
+import (
+	"fmt"
+
+	"github.com/cli/cli/v2/git"
+	"github.com/cli/cli/v2/internal/ghrepo"
+)
+
+// Remotes represents a set of git remotes
+type Remotes []*Remote
+
+// FindByName returns the first Remote whose name matches the list
+func (r Remotes) FindByName(names ...string) (*Remote, error) {
+	for _, name := range names {
+		for _, rem := range r {
+			if rem.Name == name || name == "*" {
+				return rem, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no GitHub remotes found")
+}
+
+// Remote represents a git remote mapped to a GitHub repository
+type Remote struct {
+	*git.Remote
+	Repo ghrepo.Interface
+}
+
+// RepoName returns the name of the GitHub repository
+func (r Remote) RepoName() string {
+	return r.Repo.RepoName()
+}
+
+// RepoOwner returns the name of the GitHub repository owner
+func (r Remote) RepoOwner() string {
+	return r.Repo.RepoOwner()
+}
+
+// RepoHost is the GitHub hostname that the remote points to
+func (r Remote) RepoHost() string {
+	return r.Repo.RepoHost()
+}
@@ -0,0 +1,554 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/MakeNowJust/heredoc"
+	"github.com/cli/cli/v2/api"
+	ghContext "github.com/cli/cli/v2/context"
+	"github.com/cli/cli/v2/internal/ghrepo"
+	"github.com/cli/cli/v2/internal/run"
+	"github.com/cli/cli/v2/pkg/browser"
+	"github.com/cli/cli/v2/pkg/cmdutil"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/cli/cli/v2/pkg/prompt"
+	"github.com/cli/cli/v2/utils"
+	"github.com/spf13/cobra"
+)
+
+type DefaultOptions struct {
+	HttpClient func() (*http.Client, error)
+	IO         *iostreams.IOStreams
+	Remotes    func() (ghContext.Remotes, error)
+	Browser    browser.Browser
+	Context    context.Context
+
+	Repo       ghrepo.Interface
+	Branch     string
+	ViewMode   bool
+	UnsetMode  bool
+	BrowseMode bool
+	ListMode   bool
+}
+
+func NewCmdDefault(f *cmdutil.Factory, runF func(*DefaultOptions) error) *cobra.Command {
+	opts := &DefaultOptions{
+		HttpClient: f.HttpClient,
+		IO:         f.IOStreams,
+		Remotes:    f.Remotes,
+		Browser:    f.Browser,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "default [<repository>]",
+		Short: "Configure default repository for this directory",
+		Long: heredoc.Doc(`
+			This command sets the default remote repository to use when querying the
+			GitHub API for the locally cloned repository.
+
+			gh uses the default repository for things like:
+			 - viewing and creating pull requests
+			 - viewing and creating issues
+			 - working with Actions
+			 - adding repository and environment secrets
+		`),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Context = cmd.Context()
+
+			if len(args) > 0 {
+				var err error
+				opts.Repo, err = ghrepo.FromFullName(args[0])
+				if err != nil {
+					return fmt.Errorf("expected the %q format, got %q", "[HOST/]OWNER/REPO", args[0])
+				}
+			}
+
+			if !isInsideGitRepo(opts.Context) {
+				return errors.New("must be run from inside a git repository")
+			}
+
+			if runF != nil {
+				return runF(opts)
+			}
+			return defaultRun(opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.ViewMode, "view", "v", false, "view the current default repository")
+	cmd.Flags().BoolVarP(&opts.UnsetMode, "unset", "u", false, "unset the current default repository")
+	cmd.Flags().BoolVarP(&opts.BrowseMode, "browse", "b", false, "open the default repository in the browser")
+	cmd.Flags().BoolVarP(&opts.ListMode, "list", "l", false, "list all remotes and their resolved default status")
+	cmd.Flags().StringVarP(&opts.Branch, "branch", "B", "", "set the default repository for the named branch only")
+	cmd.MarkFlagsMutuallyExclusive("view", "unset", "browse", "list", "branch")
+
+	return cmd
+}
+
+func defaultRun(opts *DefaultOptions) error {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	remotes, err := opts.Remotes()
+	if err != nil {
+		return err
+	}
+
+	if opts.ViewMode {
+		return viewDefault(ctx, opts, remotes)
+	}
+
+	if opts.UnsetMode {
+		return unsetDefault(ctx, opts, remotes)
+	}
+
+	if opts.BrowseMode {
+		return browseDefault(ctx, opts, remotes)
+	}
+
+	if opts.ListMode {
+		return listDefault(ctx, opts, remotes)
+	}
+
+	if opts.Branch != "" {
+		return setBranchDefault(ctx, opts, remotes)
+	}
+
+	httpClient, err := opts.HttpClient()
+	if err != nil {
+		return err
+	}
+
+	repos := make([]ghrepo.Interface, len(remotes))
+	for i, r := range remotes {
+		repos[i] = r.Repo
+	}
+
+	network, err := api.RepoNetwork(ctx, httpClient, repos)
+	if err != nil {
+		return err
+	}
+
+	knownIndexes := []int{}
+	for i, r := range network.Repositories {
+		if r != nil {
+			knownIndexes = append(knownIndexes, i)
+		}
+	}
+	if len(knownIndexes) == 0 {
+		return errors.New("none of the git remotes correspond to a valid remote repository")
+	}
+
+	var targetIndex int
+	var resolved string
+
+	if opts.Repo != nil {
+		targetIndex = -1
+		for _, i := range knownIndexes {
+			if ghrepo.IsSame(remotes[i].Repo, opts.Repo) {
+				targetIndex = i
+				resolved = "base"
+				break
+			}
+		}
+		if targetIndex == -1 {
+			for _, i := range knownIndexes {
+				parent := network.Repositories[i].Parent
+				if parent == nil || !ghrepo.IsSame(parent, opts.Repo) {
+					continue
+				}
+				resolved = ghrepo.FullName(opts.Repo)
+				// Record the fork relationship under the remote whose own
+				// identity the API couldn't confirm, rather than overwriting
+				// the confirmed fork remote's unrelated resolution.
+				targetIndex = i
+				for j := range remotes {
+					if network.Repositories[j] == nil {
+						targetIndex = j
+						break
+					}
+				}
+				break
+			}
+		}
+		if targetIndex == -1 {
+			return fmt.Errorf("%s does not correspond to any git remotes", ghrepo.FullName(opts.Repo))
+		}
+	} else {
+		options := make([]string, len(knownIndexes))
+		for n, i := range knownIndexes {
+			options[n] = ghrepo.FullName(remotes[i].Repo)
+		}
+
+		if len(knownIndexes) == 1 {
+			targetIndex = knownIndexes[0]
+			resolved = "base"
+		} else {
+			selected, err := promptSelectRepo(options)
+			if err != nil {
+				return err
+			}
+
+			for _, i := range knownIndexes {
+				if ghrepo.FullName(remotes[i].Repo) == selected {
+					targetIndex = i
+					resolved = "base"
+					break
+				}
+			}
+		}
+	}
+
+	for _, r := range remotes {
+		if r.Resolved != "" && r != remotes[targetIndex] {
+			if err := run.PrepareCmd(exec.CommandContext(ctx, "git", "config", "--unset", fmt.Sprintf("remote.%s.gh-resolved", r.Name))).Run(); err != nil {
+				return err
+			}
+		}
+	}
+
+	target := remotes[targetIndex]
+	if err := run.PrepareCmd(exec.CommandContext(ctx, "git", "config", "--add", fmt.Sprintf("remote.%s.gh-resolved", target.Name), resolved)).Run(); err != nil {
+		return err
+	}
+
+	if opts.IO.IsStdoutTTY() {
+		cs := opts.IO.ColorScheme()
+		fmt.Fprintf(opts.IO.Out, "%s Set %s as the default repository for the current directory\n", cs.SuccessIcon(), ghrepo.FullName(target.Repo))
+	}
+
+	return nil
+}
+
+func viewDefault(ctx context.Context, opts *DefaultOptions, remotes ghContext.Remotes) error {
+	effective, branch, override, err := ghContext.EffectiveDefault(ctx, remotes)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range effective {
+		if r.Resolved == "" {
+			continue
+		}
+		if r.Resolved == "base" {
+			fmt.Fprintf(opts.IO.Out, "%s\n", ghrepo.FullName(r.Repo))
+		} else {
+			fmt.Fprintf(opts.IO.Out, "%s\n", r.Resolved)
+		}
+		if override != "" {
+			fmt.Fprintf(opts.IO.Out, "(set for branch %q)\n", branch)
+		}
+		return nil
+	}
+
+	if override != "" {
+		fmt.Fprintf(opts.IO.Out, "%s\n", override)
+		fmt.Fprintf(opts.IO.Out, "(set for branch %q)\n", branch)
+		return nil
+	}
+
+	fmt.Fprintf(opts.IO.Out, "no default repo has been set; use `gh repo default` to select one\n")
+	return nil
+}
+
+func setBranchDefault(ctx context.Context, opts *DefaultOptions, remotes ghContext.Remotes) error {
+	httpClient, err := opts.HttpClient()
+	if err != nil {
+		return err
+	}
+
+	repos := make([]ghrepo.Interface, len(remotes))
+	for i, r := range remotes {
+		repos[i] = r.Repo
+	}
+
+	network, err := api.RepoNetwork(ctx, httpClient, repos)
+	if err != nil {
+		return err
+	}
+
+	var known []ghrepo.Interface
+	for i, r := range network.Repositories {
+		if r != nil {
+			known = append(known, remotes[i].Repo)
+		}
+	}
+	if len(known) == 0 {
+		return errors.New("none of the git remotes correspond to a valid remote repository")
+	}
+
+	var target ghrepo.Interface
+	if opts.Repo != nil {
+		for _, r := range known {
+			if ghrepo.IsSame(r, opts.Repo) {
+				target = r
+				break
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("%s does not correspond to any git remotes", ghrepo.FullName(opts.Repo))
+		}
+	} else if len(known) == 1 {
+		target = known[0]
+	} else {
+		options := make([]string, len(known))
+		for i, r := range known {
+			options[i] = ghrepo.FullName(r)
+		}
+
+		selected, err := promptSelectRepo(options)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range known {
+			if ghrepo.FullName(r) == selected {
+				target = r
+				break
+			}
+		}
+	}
+
+	key := fmt.Sprintf("branch.%s.gh-resolved", opts.Branch)
+	if err := run.PrepareCmd(exec.CommandContext(ctx, "git", "config", "--add", key, ghrepo.FullName(target))).Run(); err != nil {
+		return err
+	}
+
+	if opts.IO.IsStdoutTTY() {
+		cs := opts.IO.ColorScheme()
+		fmt.Fprintf(opts.IO.Out, "%s Set %s as the default repository for branch %q\n", cs.SuccessIcon(), ghrepo.FullName(target), opts.Branch)
+	}
+
+	return nil
+}
+
+func unsetDefault(ctx context.Context, opts *DefaultOptions, remotes ghContext.Remotes) error {
+	var cleared *ghContext.Remote
+	for _, r := range remotes {
+		if r.Resolved == "" {
+			continue
+		}
+		if err := run.PrepareCmd(exec.CommandContext(ctx, "git", "config", "--unset", fmt.Sprintf("remote.%s.gh-resolved", r.Name))).Run(); err != nil {
+			return err
+		}
+		cleared = r
+	}
+
+	branchCleared := false
+	if branch, err := ghContext.CurrentBranch(ctx); err == nil && branch != "" {
+		if override, err := ghContext.ResolveBranchDefault(ctx, branch); err == nil && override != "" {
+			if err := ghContext.ClearBranchDefault(ctx, branch); err != nil {
+				return err
+			}
+			branchCleared = true
+		}
+	}
+
+	if cleared == nil && !branchCleared {
+		return errors.New("no default repository has been set")
+	}
+
+	if opts.IO.IsStdoutTTY() {
+		cs := opts.IO.ColorScheme()
+		fmt.Fprintf(opts.IO.Out, "%s Cleared default repository for the current directory\n", cs.SuccessIcon())
+	}
+
+	return nil
+}
+
+func browseDefault(ctx context.Context, opts *DefaultOptions, remotes ghContext.Remotes) error {
+	effective, _, override, err := ghContext.EffectiveDefault(ctx, remotes)
+	if err != nil {
+		return err
+	}
+
+	var target ghrepo.Interface
+	for _, r := range effective {
+		if r.Resolved == "" {
+			continue
+		}
+		if r.Resolved == "base" {
+			target = r.Repo
+		} else {
+			var err error
+			target, err = ghrepo.FromFullName(r.Resolved)
+			if err != nil {
+				return err
+			}
+		}
+		break
+	}
+
+	if target == nil && override != "" {
+		target, err = ghrepo.FromFullName(override)
+		if err != nil {
+			return err
+		}
+	}
+
+	if target == nil {
+		httpClient, err := opts.HttpClient()
+		if err != nil {
+			return err
+		}
+
+		repos := make([]ghrepo.Interface, len(remotes))
+		for i, r := range remotes {
+			repos[i] = r.Repo
+		}
+
+		network, err := api.RepoNetwork(ctx, httpClient, repos)
+		if err != nil {
+			return err
+		}
+
+		var known []ghrepo.Interface
+		for i, r := range network.Repositories {
+			if r != nil {
+				known = append(known, remotes[i].Repo)
+			}
+		}
+		if len(known) == 0 {
+			return errors.New("none of the git remotes correspond to a valid remote repository")
+		}
+
+		if len(known) == 1 {
+			target = known[0]
+		} else {
+			options := make([]string, len(known))
+			for i, r := range known {
+				options[i] = ghrepo.FullName(r)
+			}
+
+			selected, err := promptSelectRepo(options)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range known {
+				if ghrepo.FullName(r) == selected {
+					target = r
+					break
+				}
+			}
+		}
+	}
+
+	openURL := ghrepo.GenerateRepoURL(target, "")
+	if opts.IO.IsStdoutTTY() {
+		fmt.Fprintf(opts.IO.ErrOut, "Opening %s in your browser.\n", utils.DisplayURL(openURL))
+	}
+	return opts.Browser.Browse(openURL)
+}
+
+func listDefault(ctx context.Context, opts *DefaultOptions, remotes ghContext.Remotes) error {
+	httpClient, err := opts.HttpClient()
+	if err != nil {
+		return err
+	}
+
+	repos := make([]ghrepo.Interface, len(remotes))
+	for i, r := range remotes {
+		repos[i] = r.Repo
+	}
+
+	network, err := api.RepoNetwork(ctx, httpClient, repos)
+	if err != nil {
+		return err
+	}
+
+	effective, branch, override, err := ghContext.EffectiveDefault(ctx, remotes)
+	if err != nil {
+		return err
+	}
+
+	tp := opts.IO.NewTablePrinter()
+	if opts.IO.IsStdoutTTY() {
+		tp.AddField("REMOTE", nil, nil)
+		tp.AddField("REPOSITORY", nil, nil)
+		tp.AddField("HOST", nil, nil)
+		tp.AddField("DEFAULT", nil, nil)
+		tp.EndRow()
+	}
+
+	overrideMatched := false
+	for i, r := range effective {
+		if r.Resolved == "base" {
+			overrideMatched = true
+		}
+
+		// A remote that failed to resolve against the API (dead repo, no
+		// access, etc.) is still shown so users can see why `gh` can't use
+		// it, rather than having it silently disappear from the list.
+		repoName := ghrepo.FullName(r.Repo)
+		host := r.Repo.RepoHost()
+		if i >= len(network.Repositories) || network.Repositories[i] == nil {
+			repoName = "?"
+			host = "?"
+		}
+
+		marker := ""
+		if r.Resolved != "" {
+			marker = "*"
+		}
+
+		tp.AddField(r.Name, nil, nil)
+		tp.AddField(repoName, nil, nil)
+		tp.AddField(host, nil, nil)
+		tp.AddField(marker, nil, nil)
+		tp.EndRow()
+	}
+
+	// A branch override that doesn't correspond to any known remote still
+	// needs to show up as the default, instead of leaving the real target
+	// out of the table entirely.
+	if override != "" && !overrideMatched {
+		host := "?"
+		if overrideRepo, err := ghrepo.FromFullName(override); err == nil {
+			host = overrideRepo.RepoHost()
+		}
+
+		tp.AddField(fmt.Sprintf("(branch %q)", branch), nil, nil)
+		tp.AddField(override, nil, nil)
+		tp.AddField(host, nil, nil)
+		tp.AddField("*", nil, nil)
+		tp.EndRow()
+	}
+
+	return tp.Render()
+}
+
+func promptSelectRepo(options []string) (string, error) {
+	qs := []*survey.Question{
+		{
+			Name: "repo",
+			Prompt: &survey.Select{
+				Message: "Which should be the default repository (used for e.g. querying issues) for this directory?",
+				Options: options,
+			},
+		},
+	}
+	answers := struct {
+		Repo string
+	}{}
+	if err := prompt.SurveyAsk(qs, &answers); err != nil {
+		return "", fmt.Errorf("could not prompt: %w", err)
+	}
+	return answers.Repo, nil
+}
+
+func isInsideGitRepo(ctx context.Context) bool {
+	out, err := run.PrepareCmd(exec.CommandContext(ctx, "git", "rev-parse", "--is-inside-work-tree")).Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
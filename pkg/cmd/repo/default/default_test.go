@@ -2,10 +2,11 @@ package base
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"testing"
 
-	"github.com/cli/cli/v2/context"
+	ghContext "github.com/cli/cli/v2/context"
 	"github.com/cli/cli/v2/git"
 	"github.com/cli/cli/v2/internal/ghrepo"
 	"github.com/cli/cli/v2/internal/run"
@@ -26,6 +27,14 @@ func TestNewCmdDefault(t *testing.T) {
 		wantErr  bool
 		errMsg   string
 	}{
+		{
+			name: "branch flag",
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git rev-parse --is-inside-work-tree`, 0, "true")
+			},
+			input:  "--branch feature",
+			output: DefaultOptions{Branch: "feature"},
+		},
 		{
 			name: "no argument",
 			gitStubs: func(cs *run.CommandStubber) {
@@ -57,6 +66,30 @@ func TestNewCmdDefault(t *testing.T) {
 			input:  "--view",
 			output: DefaultOptions{ViewMode: true},
 		},
+		{
+			name: "unset flag",
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git rev-parse --is-inside-work-tree`, 0, "true")
+			},
+			input:  "--unset",
+			output: DefaultOptions{UnsetMode: true},
+		},
+		{
+			name: "browse flag",
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git rev-parse --is-inside-work-tree`, 0, "true")
+			},
+			input:  "--browse",
+			output: DefaultOptions{BrowseMode: true},
+		},
+		{
+			name: "list flag",
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git rev-parse --is-inside-work-tree`, 0, "true")
+			},
+			input:  "--list",
+			output: DefaultOptions{ListMode: true},
+		},
 		{
 			name: "run from non-git directory",
 			gitStubs: func(cs *run.CommandStubber) {
@@ -66,6 +99,13 @@ func TestNewCmdDefault(t *testing.T) {
 			wantErr: true,
 			errMsg:  "must be run from inside a git repository",
 		},
+		{
+			name:     "conflicting mode flags",
+			gitStubs: func(cs *run.CommandStubber) {},
+			input:    "--list --browse",
+			wantErr:  true,
+			errMsg:   `if any flags in the group [view unset browse list branch] are set none of the others can be; [browse list] were all set`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -104,7 +144,11 @@ func TestNewCmdDefault(t *testing.T) {
 
 			assert.NoError(t, err)
 			assert.Equal(t, tt.output.Repo, gotOpts.Repo)
+			assert.Equal(t, tt.output.Branch, gotOpts.Branch)
 			assert.Equal(t, tt.output.ViewMode, gotOpts.ViewMode)
+			assert.Equal(t, tt.output.UnsetMode, gotOpts.UnsetMode)
+			assert.Equal(t, tt.output.BrowseMode, gotOpts.BrowseMode)
+			assert.Equal(t, tt.output.ListMode, gotOpts.ListMode)
 		})
 	}
 }
@@ -115,55 +159,149 @@ func TestDefaultRun(t *testing.T) {
 	repo3, _ := ghrepo.FromFullName("OWNER3/REPO3")
 
 	tests := []struct {
-		name       string
-		tty        bool
-		opts       DefaultOptions
-		remotes    []*context.Remote
-		httpStubs  func(*httpmock.Registry)
-		gitStubs   func(*run.CommandStubber)
-		askStubs   func(*prompt.AskStubber)
-		wantStdout string
-		wantErr    bool
-		errMsg     string
+		name           string
+		tty            bool
+		opts           DefaultOptions
+		remotes        []*ghContext.Remote
+		httpStubs      func(*httpmock.Registry)
+		gitStubs       func(*run.CommandStubber)
+		askStubs       func(*prompt.AskStubber)
+		wantStdout     string
+		wantStderr     string
+		wantBrowsedURL string
+		wantErr        bool
+		errMsg         string
 	}{
 		{
 			name: "view mode no current default",
 			opts: DefaultOptions{ViewMode: true},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
 				},
 			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
 			wantStdout: "no default repo has been set; use `gh repo default` to select one\n",
 		},
 		{
 			name: "view mode with base resolved current default",
 			opts: DefaultOptions{ViewMode: true},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin", Resolved: "base"},
 					Repo:   repo1,
 				},
 			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
 			wantStdout: "OWNER/REPO\n",
 		},
 		{
 			name: "view mode with non-base resolved current default",
 			opts: DefaultOptions{ViewMode: true},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin", Resolved: "PARENT/REPO"},
 					Repo:   repo1,
 				},
 			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
 			wantStdout: "PARENT/REPO\n",
 		},
+		{
+			name: "view mode with branch override no remote default",
+			opts: DefaultOptions{ViewMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "feature")
+				cs.Register(`git config --get branch\.feature\.gh-resolved`, 0, "OWNER3/REPO3")
+			},
+			wantStdout: "OWNER3/REPO3\n" + `(set for branch "feature")` + "\n",
+		},
+		{
+			name: "view mode with branch override shadowing remote-level default",
+			opts: DefaultOptions{ViewMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin", Resolved: "base"},
+					Repo:   repo1,
+				},
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "feature")
+				cs.Register(`git config --get branch\.feature\.gh-resolved`, 0, "OWNER3/REPO3")
+			},
+			wantStdout: "OWNER3/REPO3\n" + `(set for branch "feature")` + "\n",
+		},
+		{
+			name: "unset mode no current default",
+			opts: DefaultOptions{UnsetMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
+			wantErr: true,
+			errMsg:  "no default repository has been set",
+		},
+		{
+			name: "unset mode with current default",
+			tty:  true,
+			opts: DefaultOptions{UnsetMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin", Resolved: "base"},
+					Repo:   repo1,
+				},
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git config --unset remote.origin.gh-resolved`, 0, "")
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
+			wantStdout: "✓ Cleared default repository for the current directory\n",
+		},
+		{
+			name: "unset mode clears branch override shadowing an unset remote-level default",
+			tty:  true,
+			opts: DefaultOptions{UnsetMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "feature")
+				cs.Register(`git config --get branch\.feature\.gh-resolved`, 0, "OWNER3/REPO3")
+				cs.Register(`git config --unset branch\.feature\.gh-resolved`, 0, "")
+			},
+			wantStdout: "✓ Cleared default repository for the current directory\n",
+		},
 		{
 			name: "tty non-interactive mode no current default",
 			tty:  true,
 			opts: DefaultOptions{Repo: repo2},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
@@ -188,7 +326,7 @@ func TestDefaultRun(t *testing.T) {
 			name: "tty non-interactive mode set non-base default",
 			tty:  true,
 			opts: DefaultOptions{Repo: repo2},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
@@ -212,7 +350,7 @@ func TestDefaultRun(t *testing.T) {
 		{
 			name: "non-tty non-interactive mode no current default",
 			opts: DefaultOptions{Repo: repo2},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
@@ -233,11 +371,27 @@ func TestDefaultRun(t *testing.T) {
 			},
 			wantStdout: "",
 		},
+		{
+			name: "cancelled context aborts before resolving",
+			opts: DefaultOptions{Repo: repo2, Context: cancelledContext()},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+				{
+					Remote: &git.Remote{Name: "upstream"},
+					Repo:   repo2,
+				},
+			},
+			wantErr: true,
+			errMsg:  context.Canceled.Error(),
+		},
 		{
 			name: "non-interactive mode with current default",
 			tty:  true,
 			opts: DefaultOptions{Repo: repo2},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin", Resolved: "base"},
 					Repo:   repo1,
@@ -262,7 +416,7 @@ func TestDefaultRun(t *testing.T) {
 		{
 			name: "non-interactive mode no known hosts",
 			opts: DefaultOptions{Repo: repo2},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
@@ -280,7 +434,7 @@ func TestDefaultRun(t *testing.T) {
 		{
 			name: "non-interactive mode no matching remotes",
 			opts: DefaultOptions{Repo: repo2},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
@@ -299,7 +453,7 @@ func TestDefaultRun(t *testing.T) {
 			name: "interactive mode",
 			tty:  true,
 			opts: DefaultOptions{},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
@@ -329,7 +483,7 @@ func TestDefaultRun(t *testing.T) {
 			name: "interactive mode only one known host",
 			tty:  true,
 			opts: DefaultOptions{},
-			remotes: []*context.Remote{
+			remotes: []*ghContext.Remote{
 				{
 					Remote: &git.Remote{Name: "origin"},
 					Repo:   repo1,
@@ -350,6 +504,210 @@ func TestDefaultRun(t *testing.T) {
 			},
 			wantStdout: "✓ Set OWNER2/REPO2 as the default repository for the current directory\n",
 		},
+		{
+			name: "branch mode sets per-branch default",
+			tty:  true,
+			opts: DefaultOptions{Repo: repo2, Branch: "feature"},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+				{
+					Remote: &git.Remote{Name: "upstream"},
+					Repo:   repo2,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.GraphQL(`query RepositoryNetwork\b`),
+					httpmock.StringResponse(`{"data":{"repo_000":{"name":"REPO","owner":{"login":"OWNER"}},"repo_001":{"name":"REPO2","owner":{"login":"OWNER2"}}}}`),
+				)
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git config --add branch\.feature\.gh-resolved OWNER2/REPO2`, 0, "")
+			},
+			wantStdout: "✓ Set OWNER2/REPO2 as the default repository for branch \"feature\"\n",
+		},
+		{
+			name: "browse mode with current default",
+			tty:  true,
+			opts: DefaultOptions{BrowseMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin", Resolved: "base"},
+					Repo:   repo1,
+				},
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
+			wantStderr:     "Opening github.com/OWNER/REPO in your browser.\n",
+			wantBrowsedURL: "https://github.com/OWNER/REPO",
+		},
+		{
+			name: "browse mode no current default only one known host",
+			tty:  true,
+			opts: DefaultOptions{BrowseMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.GraphQL(`query RepositoryNetwork\b`),
+					httpmock.StringResponse(`{"data":{"repo_000":{"name":"REPO","owner":{"login":"OWNER"}}}}`),
+				)
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
+			wantStderr:     "Opening github.com/OWNER/REPO in your browser.\n",
+			wantBrowsedURL: "https://github.com/OWNER/REPO",
+		},
+		{
+			name: "browse mode non-tty no current default",
+			opts: DefaultOptions{BrowseMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.GraphQL(`query RepositoryNetwork\b`),
+					httpmock.StringResponse(`{"data":{"repo_000":{"name":"REPO","owner":{"login":"OWNER"}}}}`),
+				)
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
+			wantStdout:     "",
+			wantBrowsedURL: "https://github.com/OWNER/REPO",
+		},
+		{
+			name: "browse mode follows branch override instead of stale remote default",
+			tty:  true,
+			opts: DefaultOptions{BrowseMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin", Resolved: "base"},
+					Repo:   repo1,
+				},
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "feature")
+				cs.Register(`git config --get branch\.feature\.gh-resolved`, 0, "OWNER3/REPO3")
+			},
+			wantStderr:     "Opening github.com/OWNER3/REPO3 in your browser.\n",
+			wantBrowsedURL: "https://github.com/OWNER3/REPO3",
+		},
+		{
+			name: "list mode non-tty mix of resolved and unresolved",
+			opts: DefaultOptions{ListMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin", Resolved: "base"},
+					Repo:   repo1,
+				},
+				{
+					Remote: &git.Remote{Name: "upstream"},
+					Repo:   repo2,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.GraphQL(`query RepositoryNetwork\b`),
+					httpmock.StringResponse(`{"data":{"repo_000":{"name":"REPO","owner":{"login":"OWNER"}},"repo_001":{"name":"REPO2","owner":{"login":"OWNER2"}}}}`),
+				)
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
+			wantStdout: "origin\tOWNER/REPO\tgithub.com\t*\n" +
+				"upstream\tOWNER2/REPO2\tgithub.com\t\n",
+		},
+		{
+			name: "list mode non-tty shows remote that failed to resolve instead of dropping it",
+			opts: DefaultOptions{ListMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin", Resolved: "base"},
+					Repo:   repo1,
+				},
+				{
+					Remote: &git.Remote{Name: "upstream"},
+					Repo:   repo3,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.GraphQL(`query RepositoryNetwork\b`),
+					httpmock.StringResponse(`{"data":{"repo_000":{"name":"REPO","owner":{"login":"OWNER"}}}}`),
+				)
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "main")
+				cs.Register(`git config --get branch\.main\.gh-resolved`, 1, "")
+			},
+			wantStdout: "origin\tOWNER/REPO\tgithub.com\t*\n" +
+				"upstream\t?\t?\t\n",
+		},
+		{
+			name: "list mode reflects branch override instead of stale remote default",
+			opts: DefaultOptions{ListMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin", Resolved: "base"},
+					Repo:   repo1,
+				},
+				{
+					Remote: &git.Remote{Name: "upstream"},
+					Repo:   repo2,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.GraphQL(`query RepositoryNetwork\b`),
+					httpmock.StringResponse(`{"data":{"repo_000":{"name":"REPO","owner":{"login":"OWNER"}},"repo_001":{"name":"REPO2","owner":{"login":"OWNER2"}}}}`),
+				)
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "feature")
+				cs.Register(`git config --get branch\.feature\.gh-resolved`, 0, "OWNER2/REPO2")
+			},
+			wantStdout: "origin\tOWNER/REPO\tgithub.com\t\n" +
+				"upstream\tOWNER2/REPO2\tgithub.com\t*\n",
+		},
+		{
+			name: "list mode shows stale branch override that matches no remote",
+			opts: DefaultOptions{ListMode: true},
+			remotes: []*ghContext.Remote{
+				{
+					Remote: &git.Remote{Name: "origin"},
+					Repo:   repo1,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.GraphQL(`query RepositoryNetwork\b`),
+					httpmock.StringResponse(`{"data":{"repo_000":{"name":"REPO","owner":{"login":"OWNER"}}}}`),
+				)
+			},
+			gitStubs: func(cs *run.CommandStubber) {
+				cs.Register(`git branch --show-current`, 0, "feature")
+				cs.Register(`git config --get branch\.feature\.gh-resolved`, 0, "OWNER3/REPO3")
+			},
+			wantStdout: "origin\tOWNER/REPO\tgithub.com\t\n" +
+				"(branch \"feature\")\tOWNER3/REPO3\tgithub.com\t*\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -367,10 +725,16 @@ func TestDefaultRun(t *testing.T) {
 		io.SetStderrTTY(tt.tty)
 		tt.opts.IO = io
 
-		tt.opts.Remotes = func() (context.Remotes, error) {
+		tt.opts.Remotes = func() (ghContext.Remotes, error) {
 			return tt.remotes, nil
 		}
 
+		browser := &cmdutil.TestBrowser{}
+		tt.opts.Browser = browser
+
+		stderr := &bytes.Buffer{}
+		io.ErrOut = stderr
+
 		as := prompt.NewAskStubber(t)
 		if tt.askStubs != nil {
 			tt.askStubs(as)
@@ -390,6 +754,16 @@ func TestDefaultRun(t *testing.T) {
 			}
 			assert.NoError(t, err)
 			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.Equal(t, tt.wantStderr, stderr.String())
+			if tt.wantBrowsedURL != "" {
+				assert.Equal(t, tt.wantBrowsedURL, browser.BrowsedURL())
+			}
 		})
 	}
 }
+
+func cancelledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}